@@ -6,7 +6,10 @@ import (
 
 	"github.com/ViaQ/loki-operator/internal/manifests/config"
 	apps "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	core "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -14,48 +17,173 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	tlsCertVolumeName = "querier-tls-cert"
+	tlsCAVolumeName   = "querier-tls-ca"
+	tlsCertMountDir   = "/var/run/tls/querier"
+
+	queueLengthMetricName = "loki_query_frontend_queue_length"
+)
+
+// QuerierTLSOptions configures TLS termination for the querier HTTP server.
+type QuerierTLSOptions struct {
+	// CertKeySecretName is the name of the secret holding the TLS
+	// certificate and private key the querier HTTP server presents.
+	CertKeySecretName string
+	// CAConfigMapName is the name of the configmap holding the CA bundle
+	// clients are verified against.
+	CAConfigMapName string
+	// MinTLSVersion is the minimum TLS version the server will negotiate,
+	// e.g. "VersionTLS12". Defaults to the loki server default when empty.
+	MinTLSVersion string
+}
+
+// QuerierOptions configures the resources generated for the querier
+// component of a LokiStack.
+type QuerierOptions struct {
+	// Replicas is the desired number of querier replicas. Defaults to 3
+	// when nil.
+	Replicas *int32
+
+	Resources core.ResourceRequirements
+
+	NodeSelector              map[string]string
+	Tolerations               []core.Toleration
+	Affinity                  *core.Affinity
+	TopologySpreadConstraints []core.TopologySpreadConstraint
+
+	// TLS enables HTTPS for the querier HTTP server's readiness/liveness
+	// probes and client traffic when set.
+	TLS *QuerierTLSOptions
+
+	// EnableAutoscaling generates a HorizontalPodAutoscaler alongside the
+	// querier deployment, scaling it on CPU utilization and query-frontend
+	// queue length instead of a fixed replica count.
+	EnableAutoscaling bool
+}
+
+func (o QuerierOptions) replicas() int32 {
+	if o.Replicas == nil {
+		return 3
+	}
+	return *o.Replicas
+}
+
 // BuildQuerier returns a list of k8s objects for Loki Querier
-func BuildQuerier(stackName string) []client.Object {
-	return []client.Object{
-		NewQuerierDeployment(stackName),
+func BuildQuerier(stackName string, opts QuerierOptions) []client.Object {
+	objs := []client.Object{
+		NewQuerierDeployment(stackName, opts),
+		NewQuerierPodDisruptionBudget(stackName, opts),
+	}
+
+	if opts.EnableAutoscaling {
+		objs = append(objs, BuildQuerierHPA(stackName, opts))
 	}
+
+	return objs
 }
 
 // NewQuerierDeployment creates a deployment object for a querier
-func NewQuerierDeployment(stackName string) *apps.Deployment {
-	podSpec := core.PodSpec{
-		Volumes: []core.Volume{
-			{
-				Name: configVolumeName,
+func NewQuerierDeployment(stackName string, opts QuerierOptions) *apps.Deployment {
+	args := []string{
+		"-target=querier",
+		fmt.Sprintf("-config.file=%s", path.Join(config.LokiConfigMountDir, config.LokiConfigFileName)),
+	}
+
+	probeScheme := core.URISchemeHTTP
+
+	volumes := []core.Volume{
+		{
+			Name: configVolumeName,
+			VolumeSource: core.VolumeSource{
+				ConfigMap: &core.ConfigMapVolumeSource{
+					LocalObjectReference: core.LocalObjectReference{
+						Name: lokiConfigMapName(stackName),
+					},
+				},
+			},
+		},
+		{
+			Name: storageVolumeName,
+			VolumeSource: core.VolumeSource{
+				EmptyDir: &core.EmptyDirVolumeSource{},
+			},
+		},
+	}
+
+	volumeMounts := []core.VolumeMount{
+		{
+			Name:      configVolumeName,
+			ReadOnly:  false,
+			MountPath: config.LokiConfigMountDir,
+		},
+		{
+			Name:      storageVolumeName,
+			ReadOnly:  false,
+			MountPath: dataDirectory,
+		},
+	}
+
+	if opts.TLS != nil {
+		probeScheme = core.URISchemeHTTPS
+
+		volumes = append(volumes,
+			core.Volume{
+				Name: tlsCertVolumeName,
+				VolumeSource: core.VolumeSource{
+					Secret: &core.SecretVolumeSource{
+						SecretName: opts.TLS.CertKeySecretName,
+					},
+				},
+			},
+			core.Volume{
+				Name: tlsCAVolumeName,
 				VolumeSource: core.VolumeSource{
 					ConfigMap: &core.ConfigMapVolumeSource{
 						LocalObjectReference: core.LocalObjectReference{
-							Name: lokiConfigMapName(stackName),
+							Name: opts.TLS.CAConfigMapName,
 						},
 					},
 				},
 			},
-			{
-				Name: storageVolumeName,
-				VolumeSource: core.VolumeSource{
-					EmptyDir: &core.EmptyDirVolumeSource{},
-				},
+		)
+
+		volumeMounts = append(volumeMounts,
+			core.VolumeMount{
+				Name:      tlsCertVolumeName,
+				ReadOnly:  true,
+				MountPath: tlsCertMountDir,
 			},
-		},
+			core.VolumeMount{
+				Name:      tlsCAVolumeName,
+				ReadOnly:  true,
+				MountPath: path.Join(tlsCertMountDir, "ca"),
+			},
+		)
+
+		args = append(args,
+			fmt.Sprintf("-server.http-tls-cert-path=%s", path.Join(tlsCertMountDir, "tls.crt")),
+			fmt.Sprintf("-server.http-tls-key-path=%s", path.Join(tlsCertMountDir, "tls.key")),
+			fmt.Sprintf("-server.http-tls-client-ca-path=%s", path.Join(tlsCertMountDir, "ca", "ca-bundle.crt")),
+		)
+		if opts.TLS.MinTLSVersion != "" {
+			args = append(args, fmt.Sprintf("-server.http-tls-min-version=%s", opts.TLS.MinTLSVersion))
+		}
+	}
+
+	podSpec := core.PodSpec{
+		Volumes: volumes,
 		Containers: []core.Container{
 			{
 				Image: containerImage,
 				Name:  "loki-querier",
-				Args: []string{
-					"-target=querier",
-					fmt.Sprintf("-config.file=%s", path.Join(config.LokiConfigMountDir, config.LokiConfigFileName)),
-				},
+				Args:  args,
 				ReadinessProbe: &core.Probe{
 					Handler: core.Handler{
 						HTTPGet: &core.HTTPGetAction{
 							Path:   "/ready",
 							Port:   intstr.FromInt(httpPort),
-							Scheme: core.URISchemeHTTP,
+							Scheme: probeScheme,
 						},
 					},
 					InitialDelaySeconds: 15,
@@ -66,7 +194,7 @@ func NewQuerierDeployment(stackName string) *apps.Deployment {
 						HTTPGet: &core.HTTPGetAction{
 							Path:   "/metrics",
 							Port:   intstr.FromInt(httpPort),
-							Scheme: core.URISchemeHTTP,
+							Scheme: probeScheme,
 						},
 					},
 					TimeoutSeconds:   2,
@@ -87,30 +215,14 @@ func NewQuerierDeployment(stackName string) *apps.Deployment {
 						ContainerPort: gossipPort,
 					},
 				},
-				// Resources: core.ResourceRequirements{
-				// 	Limits: core.ResourceList{
-				// 		core.ResourceMemory: resource.MustParse("1Gi"),
-				// 		core.ResourceCPU:    resource.MustParse("1000m"),
-				// 	},
-				// 	Requests: core.ResourceList{
-				// 		core.ResourceMemory: resource.MustParse("50m"),
-				// 		core.ResourceCPU:    resource.MustParse("50m"),
-				// 	},
-				// },
-				VolumeMounts: []core.VolumeMount{
-					{
-						Name:      configVolumeName,
-						ReadOnly:  false,
-						MountPath: config.LokiConfigMountDir,
-					},
-					{
-						Name:      storageVolumeName,
-						ReadOnly:  false,
-						MountPath: dataDirectory,
-					},
-				},
+				Resources:    opts.Resources,
+				VolumeMounts: volumeMounts,
 			},
 		},
+		NodeSelector:              opts.NodeSelector,
+		Tolerations:               opts.Tolerations,
+		Affinity:                  opts.Affinity,
+		TopologySpreadConstraints: opts.TopologySpreadConstraints,
 	}
 
 	l := ComponentLabels("querier", stackName)
@@ -125,7 +237,7 @@ func NewQuerierDeployment(stackName string) *apps.Deployment {
 			Labels: l,
 		},
 		Spec: apps.DeploymentSpec{
-			Replicas: pointer.Int32Ptr(int32(3)),
+			Replicas: pointer.Int32Ptr(opts.replicas()),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels.Merge(l, GossipLabels()),
 			},
@@ -141,4 +253,90 @@ func NewQuerierDeployment(stackName string) *apps.Deployment {
 			},
 		},
 	}
-}
\ No newline at end of file
+}
+
+// NewQuerierPodDisruptionBudget creates a PodDisruptionBudget for the
+// querier deployment, keeping at least replicas-1 pods available so a
+// voluntary disruption (node drain, cluster upgrade) can't take out the
+// whole fleet at once. When there's only a single replica, MinAvailable is
+// 0 so the budget never blocks a drain outright.
+func NewQuerierPodDisruptionBudget(stackName string, opts QuerierOptions) *policyv1.PodDisruptionBudget {
+	l := ComponentLabels("querier", stackName)
+
+	minAvailable := int(opts.replicas()) - 1
+	if minAvailable < 0 {
+		minAvailable = 0
+	}
+	minAvailableIntStr := intstr.FromInt(minAvailable)
+
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("loki-querier-%s", stackName),
+			Labels: l,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailableIntStr,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels.Merge(l, GossipLabels()),
+			},
+		},
+	}
+}
+
+// BuildQuerierHPA returns a HorizontalPodAutoscaler that scales the querier
+// deployment on CPU utilization and on the query-frontend's queue length,
+// so the fleet grows with backlog rather than only with CPU pressure.
+func BuildQuerierHPA(stackName string, opts QuerierOptions) *autoscalingv2.HorizontalPodAutoscaler {
+	minReplicas := opts.replicas()
+	maxReplicas := minReplicas * 3
+
+	l := ComponentLabels("querier", stackName)
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: autoscalingv2.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("loki-querier-%s", stackName),
+			Labels: l,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				Kind:       "Deployment",
+				Name:       fmt.Sprintf("loki-querier-%s", stackName),
+				APIVersion: apps.SchemeGroupVersion.String(),
+			},
+			MinReplicas: pointer.Int32Ptr(minReplicas),
+			MaxReplicas: maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{
+				{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name: core.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{
+							Type:               autoscalingv2.UtilizationMetricType,
+							AverageUtilization: pointer.Int32Ptr(80),
+						},
+					},
+				},
+				{
+					Type: autoscalingv2.PodsMetricSourceType,
+					Pods: &autoscalingv2.PodsMetricSource{
+						Metric: autoscalingv2.MetricIdentifier{
+							Name: queueLengthMetricName,
+						},
+						Target: autoscalingv2.MetricTarget{
+							Type:         autoscalingv2.AverageValueMetricType,
+							AverageValue: resource.NewQuantity(100, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+}
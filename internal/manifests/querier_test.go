@@ -0,0 +1,124 @@
+package manifests
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQuerierDeployment_DefaultsToHTTP(t *testing.T) {
+	dep := NewQuerierDeployment("test", QuerierOptions{})
+
+	container := dep.Spec.Template.Spec.Containers[0]
+	require.Equal(t, core.URISchemeHTTP, container.ReadinessProbe.HTTPGet.Scheme)
+	require.Equal(t, core.URISchemeHTTP, container.LivenessProbe.HTTPGet.Scheme)
+
+	for _, arg := range container.Args {
+		require.NotContains(t, arg, "-server.http-tls")
+	}
+	for _, v := range dep.Spec.Template.Spec.Volumes {
+		require.NotEqual(t, tlsCertVolumeName, v.Name)
+		require.NotEqual(t, tlsCAVolumeName, v.Name)
+	}
+}
+
+func TestNewQuerierDeployment_TLSMountsVolumesAndArgs(t *testing.T) {
+	opts := QuerierOptions{
+		TLS: &QuerierTLSOptions{
+			CertKeySecretName: "querier-tls",
+			CAConfigMapName:   "querier-ca",
+			MinTLSVersion:     "VersionTLS12",
+		},
+	}
+
+	dep := NewQuerierDeployment("test", opts)
+	container := dep.Spec.Template.Spec.Containers[0]
+
+	require.Equal(t, core.URISchemeHTTPS, container.ReadinessProbe.HTTPGet.Scheme)
+	require.Equal(t, core.URISchemeHTTPS, container.LivenessProbe.HTTPGet.Scheme)
+
+	require.Contains(t, container.Args, "-server.http-tls-cert-path=/var/run/tls/querier/tls.crt")
+	require.Contains(t, container.Args, "-server.http-tls-key-path=/var/run/tls/querier/tls.key")
+	require.Contains(t, container.Args, "-server.http-tls-client-ca-path=/var/run/tls/querier/ca/ca-bundle.crt")
+	require.Contains(t, container.Args, "-server.http-tls-min-version=VersionTLS12")
+
+	var sawCertVolume, sawCAVolume bool
+	for _, v := range dep.Spec.Template.Spec.Volumes {
+		switch v.Name {
+		case tlsCertVolumeName:
+			sawCertVolume = true
+			require.Equal(t, opts.TLS.CertKeySecretName, v.Secret.SecretName)
+		case tlsCAVolumeName:
+			sawCAVolume = true
+			require.Equal(t, opts.TLS.CAConfigMapName, v.ConfigMap.Name)
+		}
+	}
+	require.True(t, sawCertVolume, "expected a TLS cert volume")
+	require.True(t, sawCAVolume, "expected a TLS CA volume")
+
+	var sawCertMount, sawCAMount bool
+	for _, m := range container.VolumeMounts {
+		switch m.Name {
+		case tlsCertVolumeName:
+			sawCertMount = true
+		case tlsCAVolumeName:
+			sawCAMount = true
+		}
+	}
+	require.True(t, sawCertMount, "expected a TLS cert volume mount")
+	require.True(t, sawCAMount, "expected a TLS CA volume mount")
+}
+
+func TestNewQuerierDeployment_ReplicasDefaultAndOverride(t *testing.T) {
+	dep := NewQuerierDeployment("test", QuerierOptions{})
+	require.EqualValues(t, 3, *dep.Spec.Replicas)
+
+	dep = NewQuerierDeployment("test", QuerierOptions{Replicas: pointer.Int32Ptr(5)})
+	require.EqualValues(t, 5, *dep.Spec.Replicas)
+}
+
+func TestNewQuerierPodDisruptionBudget_MinAvailable(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		replicas int32
+		want     int
+	}{
+		{"default replicas", 3, 2},
+		{"single replica never blocks a drain", 1, 0},
+		{"zero replicas floors at zero", 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			replicas := tc.replicas
+			pdb := NewQuerierPodDisruptionBudget("test", QuerierOptions{Replicas: &replicas})
+			require.Equal(t, intstr.FromInt(tc.want), *pdb.Spec.MinAvailable)
+		})
+	}
+}
+
+func TestBuildQuerierHPA_ReplicaBoundsAndMetrics(t *testing.T) {
+	replicas := int32(4)
+	hpa := BuildQuerierHPA("test", QuerierOptions{Replicas: &replicas})
+
+	require.EqualValues(t, 4, *hpa.Spec.MinReplicas)
+	require.EqualValues(t, 12, hpa.Spec.MaxReplicas)
+	require.Len(t, hpa.Spec.Metrics, 2)
+
+	cpu := hpa.Spec.Metrics[0]
+	require.Equal(t, core.ResourceCPU, cpu.Resource.Name)
+	require.EqualValues(t, 80, *cpu.Resource.Target.AverageUtilization)
+
+	queueLength := hpa.Spec.Metrics[1]
+	require.Equal(t, queueLengthMetricName, queueLength.Pods.Metric.Name)
+}
+
+func TestBuildQuerier_OnlyIncludesHPAWhenAutoscalingEnabled(t *testing.T) {
+	objs := BuildQuerier("test", QuerierOptions{})
+	require.Len(t, objs, 2)
+
+	objs = BuildQuerier("test", QuerierOptions{EnableAutoscaling: true})
+	require.Len(t, objs, 3)
+}
@@ -0,0 +1,25 @@
+package bloomcompactor
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+
+	"github.com/grafana/loki/pkg/bloomutils"
+)
+
+// validateRingOnBootstrap is called before the compactor starts operating
+// on a ring it just joined. Operators sometimes reuse a ring across
+// components, which can leave it with a mix of single- and multi-token
+// instances; sharding work across that silently would strand or duplicate
+// fingerprint ranges, so unless the operator has opted into multi-token
+// mode the compactor refuses to start.
+func validateRingOnBootstrap(instances []ring.InstanceDesc, allowMultiToken bool, logger log.Logger) error {
+	if err := bloomutils.ValidateInstanceTokens(instances, allowMultiToken); err != nil {
+		level.Error(logger).Log("msg", "refusing to start bloom compactor", "err", err)
+		return fmt.Errorf("bloom compactor bootstrap: %w", err)
+	}
+	return nil
+}
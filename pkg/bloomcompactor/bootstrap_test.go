@@ -0,0 +1,25 @@
+package bloomcompactor
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRingOnBootstrap(t *testing.T) {
+	mismatched := []ring.InstanceDesc{
+		{Id: "a", Tokens: []uint32{0}},
+		{Id: "b", Tokens: []uint32{1000, 2000}},
+	}
+
+	require.Error(t, validateRingOnBootstrap(mismatched, false, log.NewNopLogger()))
+	require.NoError(t, validateRingOnBootstrap(mismatched, true, log.NewNopLogger()))
+
+	uniform := []ring.InstanceDesc{
+		{Id: "a", Tokens: []uint32{0}},
+		{Id: "b", Tokens: []uint32{1000}},
+	}
+	require.NoError(t, validateRingOnBootstrap(uniform, false, log.NewNopLogger()))
+}
@@ -0,0 +1,55 @@
+// Package bloomcompactor contains the sharding logic the bloom compactor
+// uses to split fingerprint ranges across its ring instances.
+package bloomcompactor
+
+import (
+	"fmt"
+
+	"github.com/grafana/dskit/ring"
+
+	"github.com/grafana/loki/pkg/bloomutils"
+	v1 "github.com/grafana/loki/pkg/storage/bloom/v1"
+)
+
+// CompactionJob is a single fingerprint range an instance is responsible
+// for compacting.
+type CompactionJob struct {
+	Bounds v1.FingerprintBounds
+}
+
+// planCompactionJobs returns one CompactionJob per fingerprint range owned
+// by the instance with the given id, honoring replicationFactor so a
+// single failed instance no longer strands a fingerprint range. With
+// replicationFactor <= 1 this is equivalent to the single-owner sharding
+// the compactor has always used.
+//
+// GetInstanceOwnedRanges assumes single-token instances, so a multi-token
+// ring with replicationFactor > 1 would otherwise silently ignore every
+// token past each instance's first and mis-shard the keyspace. Bootstrap
+// validation only rejects *mismatched* token counts, so a uniformly
+// multi-token ring can still reach here; refuse rather than plan wrong
+// jobs.
+func planCompactionJobs(id string, instances []ring.InstanceDesc, replicationFactor int) ([]CompactionJob, error) {
+	var (
+		ranges []v1.FingerprintBounds
+		err    error
+	)
+
+	if replicationFactor <= 1 {
+		ranges, err = bloomutils.GetInstanceWithTokenRange(id, instances)
+	} else {
+		if bloomutils.HasMultipleTokens(instances) {
+			return nil, fmt.Errorf("cannot plan compaction jobs with replicationFactor %d: ring contains multi-token instances, which GetInstanceOwnedRanges does not support", replicationFactor)
+		}
+		ranges, err = bloomutils.GetInstanceOwnedRanges(id, instances, replicationFactor)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]CompactionJob, 0, len(ranges))
+	for _, bounds := range ranges {
+		jobs = append(jobs, CompactionJob{Bounds: bounds})
+	}
+	return jobs, nil
+}
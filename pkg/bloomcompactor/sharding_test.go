@@ -0,0 +1,47 @@
+package bloomcompactor
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCompactionJobs(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		{Id: "a", Zone: "zone-a", Tokens: []uint32{0}},
+		{Id: "b", Zone: "zone-b", Tokens: []uint32{1000}},
+		{Id: "c", Zone: "zone-c", Tokens: []uint32{2000}},
+	}
+
+	jobsRF1, err := planCompactionJobs("a", append([]ring.InstanceDesc{}, instances...), 1)
+	require.NoError(t, err)
+	require.Len(t, jobsRF1, 1)
+
+	jobsRF2, err := planCompactionJobs("a", append([]ring.InstanceDesc{}, instances...), 2)
+	require.NoError(t, err)
+	require.NotEmpty(t, jobsRF2)
+
+	_, err = planCompactionJobs("missing", append([]ring.InstanceDesc{}, instances...), 1)
+	require.Error(t, err)
+}
+
+func TestPlanCompactionJobs_RefusesMultiTokenRingWithReplicationFactor(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		{Id: "a", Zone: "zone-a", Tokens: []uint32{0, 4000}},
+		{Id: "b", Zone: "zone-b", Tokens: []uint32{1000, 5000}},
+		{Id: "c", Zone: "zone-c", Tokens: []uint32{2000, 6000}},
+	}
+
+	// A uniformly multi-token ring passes bootstrap's mismatched-token-count
+	// check, so planCompactionJobs itself must refuse rather than silently
+	// mis-shard via GetInstanceOwnedRanges.
+	_, err := planCompactionJobs("a", append([]ring.InstanceDesc{}, instances...), 2)
+	require.Error(t, err)
+
+	// replicationFactor <= 1 still works, since that path never calls
+	// GetInstanceOwnedRanges.
+	jobs, err := planCompactionJobs("a", append([]ring.InstanceDesc{}, instances...), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, jobs)
+}
@@ -0,0 +1,18 @@
+// Package bloomgateway contains the client-side logic for looking up
+// bloom filters from the bloom gateway ring.
+package bloomgateway
+
+import (
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/loki/pkg/bloomutils"
+)
+
+// replicasForFingerprint returns the up to replicationFactor gateway
+// instances that can serve a lookup for fp, in preference order. Callers
+// should try each in turn so that a single failed instance doesn't fail
+// the whole lookup.
+func replicasForFingerprint(fp model.Fingerprint, instances []ring.InstanceDesc, replicationFactor int) []ring.InstanceDesc {
+	return bloomutils.GetReplicasForToken(fp, instances, replicationFactor)
+}
@@ -0,0 +1,44 @@
+package bloomgateway
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/pkg/bloomutils"
+)
+
+func TestReplicasForFingerprint(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		{Id: "a", Zone: "zone-a", Tokens: []uint32{0}},
+		{Id: "b", Zone: "zone-b", Tokens: []uint32{1000}},
+		{Id: "c", Zone: "zone-c", Tokens: []uint32{2000}},
+	}
+
+	replicas := replicasForFingerprint(1500, instances, 2)
+	require.Len(t, replicas, 2)
+}
+
+func TestReplicasForFingerprint_MatchesOwnedRangesForRealFingerprint(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		{Id: "a", Zone: "zone-a", Tokens: []uint32{0}},
+		{Id: "b", Zone: "zone-b", Tokens: []uint32{1000}},
+		{Id: "c", Zone: "zone-c", Tokens: []uint32{2000}},
+		{Id: "d", Zone: "zone-d", Tokens: []uint32{3000}},
+	}
+
+	// A real, 64-bit-scale stream fingerprint, not a value small enough to
+	// land in shard 0 regardless of which keyspace it's divided against.
+	fp := model.Fingerprint(0x9999999912345678)
+
+	replicas := replicasForFingerprint(fp, append([]ring.InstanceDesc{}, instances...), 1)
+	require.Len(t, replicas, 1)
+
+	owned, err := bloomutils.GetInstanceOwnedRanges(replicas[0].Id, append([]ring.InstanceDesc{}, instances...), 1)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	require.True(t, uint64(owned[0].Min) <= uint64(fp) && uint64(fp) <= uint64(owned[0].Max),
+		"replicasForFingerprint must pick the instance GetInstanceOwnedRanges says owns fp")
+}
@@ -4,6 +4,7 @@
 package bloomutils
 
 import (
+	"fmt"
 	"math"
 	"sort"
 
@@ -39,45 +40,230 @@ func (i InstancesWithTokenRange) Contains(token uint32) bool {
 	return false
 }
 
-// GetInstanceWithTokenRange calculates the token range for a specific instance
-// with given id based on the first token in the ring.
-// This assumes that each instance in the ring is configured with only a single
-// token.
-func GetInstanceWithTokenRange(id string, instances []ring.InstanceDesc) (v1.FingerprintBounds, error) {
+// GetInstanceWithTokenRange calculates the token ranges owned by a specific
+// instance with given id.
+//
+// When every instance in the ring holds exactly one token (e.g. the bloom
+// compactor ring in its default mode), the result is the single disjoint
+// segment of the keyspace computed from the instance's position among the
+// sorted first tokens. When instances hold multiple tokens -- which can
+// happen when a ring is reused across components -- this delegates to the
+// same sort-merge iterator used by GetInstancesWithTokenRanges and unions
+// every bounds pair owned by id into the returned slice, so callers don't
+// need to special-case multi-token rings themselves.
+func GetInstanceWithTokenRange(id string, instances []ring.InstanceDesc) ([]v1.FingerprintBounds, error) {
+	if HasMultipleTokens(instances) {
+		servers := GetInstancesWithTokenRanges(id, instances)
+		if len(servers) == 0 {
+			return nil, ring.ErrInstanceNotFound
+		}
 
-	// Sort instances -- they may not be sorted
-	// because they're usually accessed by looking up the tokens (which are sorted)
-	sort.Slice(instances, func(i, j int) bool {
-		return instances[i].Tokens[0] < instances[j].Tokens[0]
-	})
+		bounds := make([]v1.FingerprintBounds, 0, len(servers))
+		for _, server := range servers {
+			bounds = append(bounds, v1.NewBounds(model.Fingerprint(server.MinToken), model.Fingerprint(server.MaxToken)))
+		}
+		return bounds, nil
+	}
 
-	idx := slices.IndexFunc(instances, func(inst ring.InstanceDesc) bool {
+	sorted := sortInstancesByFirstToken(instances)
+
+	idx := slices.IndexFunc(sorted, func(inst ring.InstanceDesc) bool {
 		return inst.Id == id
 	})
 
 	// instance with Id == id not found
 	if idx == -1 {
-		return v1.FingerprintBounds{}, ring.ErrInstanceNotFound
+		return nil, ring.ErrInstanceNotFound
+	}
+
+	minToken, maxToken := singleTokenShardBounds(uint64(idx), uint64(len(sorted)))
+	return []v1.FingerprintBounds{v1.NewBounds(minToken, maxToken)}, nil
+}
+
+// HasMultipleTokens reports whether any instance in the ring holds more
+// than one token.
+func HasMultipleTokens(instances []ring.InstanceDesc) bool {
+	for _, inst := range instances {
+		if len(inst.Tokens) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateInstanceTokens checks that every instance in the ring holds the
+// same number of tokens. A ring with a mix of single- and multi-token
+// instances usually means the ring is being reused by a component that
+// hasn't opted into multi-token mode, and sharding work across it silently
+// would strand or duplicate fingerprint ranges. Callers that have opted
+// into multi-token rings (allowMultiToken) skip this check, since
+// GetInstanceWithTokenRange and GetInstancesWithTokenRanges both handle
+// mixed-but-consistent multi-token counts correctly.
+func ValidateInstanceTokens(instances []ring.InstanceDesc, allowMultiToken bool) error {
+	if allowMultiToken || len(instances) == 0 {
+		return nil
+	}
+
+	want := len(instances[0].Tokens)
+	for _, inst := range instances[1:] {
+		if len(inst.Tokens) != want {
+			return fmt.Errorf("ring contains instances with mismatched token counts (%d vs %d); enable multi-token mode to proceed", want, len(inst.Tokens))
+		}
+	}
+	return nil
+}
+
+// GetInstanceOwnedRanges calculates the token ranges owned by the instance
+// with the given id, assuming a replication factor of rf. Unlike
+// GetInstanceWithTokenRange, which gives each instance only the segments
+// of the keyspace it alone owns, this walks the sorted ring rf times
+// (analogous to how Cassandra/DynamoDB compute natural replicas) so that
+// every fingerprint is owned by up to rf distinct instances. This also
+// assumes that each instance in the ring is configured with only a single
+// token; use GetInstancesWithTokenRanges for multi-token rings.
+//
+// The returned bounds are deterministic and independent of the input
+// ordering of instances. Placement is zone-aware: a replica set will not
+// contain two instances from the same zone unless there are fewer than rf
+// distinct zones in the ring, in which case the zone constraint is relaxed
+// so rf replicas can still be produced.
+func GetInstanceOwnedRanges(id string, instances []ring.InstanceDesc, rf int) ([]v1.FingerprintBounds, error) {
+	if rf < 1 {
+		return nil, fmt.Errorf("replication factor must be >= 1, got %d", rf)
+	}
+
+	sorted := sortInstancesByFirstToken(instances)
+
+	idx := slices.IndexFunc(sorted, func(inst ring.InstanceDesc) bool {
+		return inst.Id == id
+	})
+	if idx == -1 {
+		return nil, ring.ErrInstanceNotFound
+	}
+
+	n := uint64(len(sorted))
+	var bounds []v1.FingerprintBounds
+	for shard := uint64(0); shard < n; shard++ {
+		if !shardReplicas(sorted, shard, rf).contains(id) {
+			continue
+		}
+		minToken, maxToken := singleTokenShardBounds(shard, n)
+		bounds = append(bounds, v1.NewBounds(minToken, maxToken))
 	}
 
-	i := uint64(idx)
-	n := uint64(len(instances))
+	return bounds, nil
+}
+
+// GetReplicasForToken returns the up to rf instances responsible for
+// serving the given fingerprint, using the same zone-aware natural-replica
+// placement as GetInstanceOwnedRanges.
+//
+// fp must be in the same 64-bit model.Fingerprint keyspace that
+// GetInstanceOwnedRanges/singleTokenShardBounds partition -- not the
+// 32-bit ring token space NewInstanceSortMergeIterator walks -- so that a
+// real stream fingerprint resolves to the same shard GetInstanceOwnedRanges
+// says owns it. Truncating a fingerprint to 32 bits before calling this
+// would pick an essentially random shard, since the low bits of a
+// fingerprint carry no information about its position in the 64-bit
+// keyspace.
+func GetReplicasForToken(fp model.Fingerprint, instances []ring.InstanceDesc, rf int) []ring.InstanceDesc {
+	sorted := sortInstancesByFirstToken(instances)
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	n := uint64(len(sorted))
+	step := uint64(math.MaxUint64) / n
+	shard := uint64(fp) / step
+	if shard >= n {
+		// the last shard absorbs the remainder left by the integer
+		// division above, so fingerprints beyond step*n still belong to it
+		shard = n - 1
+	}
+
+	return shardReplicas(sorted, shard, rf)
+}
+
+// singleTokenShardBounds returns the [min, max] fingerprint bounds owned by
+// the shard-th of n equally sized partitions of the uint64 keyspace. The
+// last shard absorbs the remainder of the division so the partitions cover
+// the keyspace exactly, with no gap between math.MaxUint64 and the
+// wrap-around back to 0.
+func singleTokenShardBounds(shard, n uint64) (model.Fingerprint, model.Fingerprint) {
 	step := math.MaxUint64 / n
 
-	minToken := model.Fingerprint(step * i)
-	maxToken := model.Fingerprint(step*i + step - 1)
-	if i == n-1 {
-		// extend the last token tange to MaxUint32
+	minToken := model.Fingerprint(step * shard)
+	maxToken := model.Fingerprint(step*shard + step - 1)
+	if shard == n-1 {
 		maxToken = math.MaxUint64
 	}
 
-	return v1.NewBounds(minToken, maxToken), nil
+	return minToken, maxToken
+}
+
+// shardReplicas returns the up to rf instances that own shard (out of the n
+// shards sorted has been partitioned into), walking the ring forward from
+// shard and wrapping around past the last instance back to the first. It
+// skips candidates whose zone is already represented in the replica set,
+// analogous to dskit's zone-aware ring, unless fewer than rf distinct zones
+// exist in sorted, in which case the zone constraint is relaxed.
+func shardReplicas(sorted []ring.InstanceDesc, shard uint64, rf int) instanceSet {
+	n := len(sorted)
+	if rf > n {
+		rf = n
+	}
+
+	relaxZones := countDistinctZones(sorted) < rf
+
+	replicas := make(instanceSet, 0, rf)
+	seenZones := make(map[string]struct{}, rf)
+	for offset := 0; offset < n && len(replicas) < rf; offset++ {
+		candidate := sorted[(int(shard)+offset)%n]
+		if !relaxZones {
+			if _, ok := seenZones[candidate.Zone]; ok {
+				continue
+			}
+		}
+		replicas = append(replicas, candidate)
+		seenZones[candidate.Zone] = struct{}{}
+	}
+
+	return replicas
+}
+
+// instanceSet is an ordered list of ring instances returned from a replica
+// placement lookup.
+type instanceSet []ring.InstanceDesc
+
+func (s instanceSet) contains(id string) bool {
+	for _, inst := range s {
+		if inst.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func countDistinctZones(instances []ring.InstanceDesc) int {
+	zones := make(map[string]struct{}, len(instances))
+	for _, inst := range instances {
+		zones[inst.Zone] = struct{}{}
+	}
+	return len(zones)
+}
+
+// sortInstancesByFirstToken sorts instances by their first token in place
+// and returns them. Instances may not already be sorted because they're
+// usually accessed by looking up the tokens (which are sorted).
+func sortInstancesByFirstToken(instances []ring.InstanceDesc) []ring.InstanceDesc {
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Tokens[0] < instances[j].Tokens[0]
+	})
+	return instances
 }
 
 // GetInstancesWithTokenRanges calculates the token ranges for a specific
 // instance with given id based on all tokens in the ring.
-// If the instances in the ring are configured with a single token, such as the
-// bloom compactor, use GetInstanceWithTokenRange() instead.
 func GetInstancesWithTokenRanges(id string, instances []ring.InstanceDesc) InstancesWithTokenRange {
 	servers := make([]InstanceWithTokenRange, 0, len(instances))
 	it := NewInstanceSortMergeIterator(instances)
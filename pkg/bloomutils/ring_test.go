@@ -0,0 +1,217 @@
+package bloomutils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func mkInstance(id, zone string, token uint32) ring.InstanceDesc {
+	return ring.InstanceDesc{
+		Id:     id,
+		Zone:   zone,
+		Tokens: []uint32{token},
+	}
+}
+
+func mkMultiTokenInstance(id, zone string, tokens ...uint32) ring.InstanceDesc {
+	return ring.InstanceDesc{
+		Id:     id,
+		Zone:   zone,
+		Tokens: tokens,
+	}
+}
+
+func TestGetInstanceWithTokenRange_MultiToken(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkMultiTokenInstance("a", "zone-a", 0, 4000),
+		mkMultiTokenInstance("b", "zone-b", 1000, 5000),
+		mkMultiTokenInstance("c", "zone-c", 2000, 6000),
+	}
+
+	bounds, err := GetInstanceWithTokenRange("b", append([]ring.InstanceDesc{}, instances...))
+	require.NoError(t, err)
+	require.Len(t, bounds, 2)
+}
+
+func TestGetInstanceWithTokenRange_NotFound(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+	}
+
+	_, err := GetInstanceWithTokenRange("missing", instances)
+	require.ErrorIs(t, err, ring.ErrInstanceNotFound)
+}
+
+func TestValidateInstanceTokens(t *testing.T) {
+	mismatched := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkMultiTokenInstance("b", "zone-b", 1000, 5000),
+	}
+
+	require.Error(t, ValidateInstanceTokens(mismatched, false))
+	require.NoError(t, ValidateInstanceTokens(mismatched, true))
+
+	uniform := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+	}
+	require.NoError(t, ValidateInstanceTokens(uniform, false))
+}
+
+func TestGetInstanceOwnedRanges_DeterministicRegardlessOfOrder(t *testing.T) {
+	base := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+		mkInstance("c", "zone-c", 2000),
+		mkInstance("d", "zone-a", 3000),
+	}
+
+	shuffled := make([]ring.InstanceDesc, len(base))
+	copy(shuffled, base)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		want, err := GetInstanceOwnedRanges(id, append([]ring.InstanceDesc{}, base...), 2)
+		require.NoError(t, err)
+
+		got, err := GetInstanceOwnedRanges(id, append([]ring.InstanceDesc{}, shuffled...), 2)
+		require.NoError(t, err)
+
+		require.Equal(t, want, got)
+	}
+}
+
+func TestGetInstanceOwnedRanges_RFOne_MatchesSingleOwner(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+		mkInstance("c", "zone-c", 2000),
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		single, err := GetInstanceWithTokenRange(id, append([]ring.InstanceDesc{}, instances...))
+		require.NoError(t, err)
+		require.Len(t, single, 1)
+
+		owned, err := GetInstanceOwnedRanges(id, append([]ring.InstanceDesc{}, instances...), 1)
+		require.NoError(t, err)
+
+		require.Len(t, owned, 1)
+		require.Equal(t, single[0], owned[0])
+	}
+}
+
+func TestGetInstanceOwnedRanges_LastShardCoversMaxUint64(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+	}
+
+	owned, err := GetInstanceOwnedRanges("b", instances, 1)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	require.EqualValues(t, math.MaxUint64, owned[0].Max)
+}
+
+func TestGetInstanceOwnedRanges_ZoneAwarePlacement(t *testing.T) {
+	// Two instances per zone; RF=2 should never pick two replicas from the
+	// same zone when enough zones are available.
+	instances := []ring.InstanceDesc{
+		mkInstance("a1", "zone-a", 0),
+		mkInstance("b1", "zone-b", 1000),
+		mkInstance("a2", "zone-a", 2000),
+		mkInstance("b2", "zone-b", 3000),
+	}
+
+	for shard := uint64(0); shard < uint64(len(instances)); shard++ {
+		replicas := shardReplicas(sortInstancesByFirstToken(append([]ring.InstanceDesc{}, instances...)), shard, 2)
+		require.Len(t, replicas, 2)
+		require.NotEqual(t, replicas[0].Zone, replicas[1].Zone)
+	}
+}
+
+func TestGetInstanceOwnedRanges_RelaxesZoneConstraintWhenTooFewZones(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-a", 1000),
+		mkInstance("c", "zone-a", 2000),
+	}
+
+	owned, err := GetInstanceOwnedRanges("a", instances, 3)
+	require.NoError(t, err)
+	require.NotEmpty(t, owned)
+}
+
+func TestGetReplicasForToken_MatchesOwnedRanges(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+		mkInstance("c", "zone-c", 2000),
+	}
+
+	// Deliberately not a tiny value: a fingerprint two thirds of the way up
+	// the 64-bit keyspace (the first value of the last of three shards), so
+	// this only passes if the shard lookup actually divides the same domain
+	// GetInstanceOwnedRanges does.
+	fp := model.Fingerprint(math.MaxUint64 / 3 * 2)
+
+	replicas := GetReplicasForToken(fp, append([]ring.InstanceDesc{}, instances...), 2)
+	require.Len(t, replicas, 2)
+
+	for _, r := range replicas {
+		owned, err := GetInstanceOwnedRanges(r.Id, append([]ring.InstanceDesc{}, instances...), 2)
+		require.NoError(t, err)
+
+		found := false
+		for _, b := range owned {
+			if uint64(b.Min) <= uint64(fp) && uint64(fp) <= uint64(b.Max) {
+				found = true
+			}
+		}
+		require.True(t, found, "instance %s should own fingerprint %d", r.Id, fp)
+	}
+}
+
+func TestGetReplicasForToken_DifferentFingerprintsMapToDifferentShards(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+		mkInstance("c", "zone-c", 2000),
+	}
+
+	low := GetReplicasForToken(1500, append([]ring.InstanceDesc{}, instances...), 1)
+	high := GetReplicasForToken(model.Fingerprint(math.MaxUint64-1), append([]ring.InstanceDesc{}, instances...), 1)
+
+	require.Len(t, low, 1)
+	require.Len(t, high, 1)
+	require.NotEqual(t, low[0].Id, high[0].Id, "fingerprints in different shards of the 64-bit keyspace must not resolve to the same owner")
+}
+
+func TestGetReplicasForToken_RegressionForTruncatedFingerprint(t *testing.T) {
+	instances := []ring.InstanceDesc{
+		mkInstance("a", "zone-a", 0),
+		mkInstance("b", "zone-b", 1000),
+		mkInstance("c", "zone-c", 2000),
+		mkInstance("d", "zone-d", 3000),
+	}
+
+	// This fingerprint's low 32 bits alone would resolve to shard 0 under
+	// a (buggy) 32-bit division, but it actually falls in shard 2 of the
+	// real 64-bit keyspace GetInstanceOwnedRanges partitions.
+	fp := model.Fingerprint(0x9999999912345678)
+
+	replicas := GetReplicasForToken(fp, append([]ring.InstanceDesc{}, instances...), 1)
+	require.Len(t, replicas, 1)
+
+	owned, err := GetInstanceOwnedRanges(replicas[0].Id, append([]ring.InstanceDesc{}, instances...), 1)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	require.True(t, uint64(owned[0].Min) <= uint64(fp) && uint64(fp) <= uint64(owned[0].Max),
+		"GetReplicasForToken(%#x) must pick the instance GetInstanceOwnedRanges says owns it", uint64(fp))
+}
@@ -0,0 +1,219 @@
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/loki/pkg/logproto"
+)
+
+// tenantIDLabel is stamped onto every stream a multiTenantTailer emits so
+// a caller federating across tenants can still attribute each line back to
+// the tenant it came from.
+const tenantIDLabel = "__tenant_id__"
+
+// multiTenantTailer federates a single tail connection across a
+// caller-specified set of tenants. It keeps one tailer per tenant
+// internally -- each evaluating its own matchers against its own tenant's
+// streams -- and multiplexes their output through a bounded fan-in channel
+// into the single TailServer the caller is talking to.
+type multiTenantTailer struct {
+	conn TailServer
+
+	tailersMtx sync.RWMutex
+	tailers    map[string]*tailer
+
+	fanIn chan *logproto.Stream
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// newMultiTenantTailer builds a tailer that federates query across every
+// tenant in tenantIDs. tenantIDs is checked against allowedTenantIDs --
+// resolved by the caller from the request's comma-delimited X-Scope-OrgID
+// header, the same convention Loki uses elsewhere for tenant multiplexing
+// -- and the call fails if it asks for a tenant the caller isn't
+// authorized to tail. Each authorized tenant gets its own tailer instance
+// with query parsed and matched independently, so a matcher that only
+// makes sense for one tenant's label set doesn't affect the others.
+func newMultiTenantTailer(tenantIDs, allowedTenantIDs []string, query string, server TailServer, maxDroppedStreams int) (*multiTenantTailer, error) {
+	if len(tenantIDs) == 0 {
+		return nil, fmt.Errorf("multi-tenant tailer requires at least one tenant id")
+	}
+
+	authorized, err := AuthorizeTenantIDs(tenantIDs, allowedTenantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	mt := &multiTenantTailer{
+		conn:    server,
+		tailers: make(map[string]*tailer, len(authorized)),
+		// Buffered so one slow or misbehaving tenant can't block the
+		// others from handing off entries; overflow past this buffer is
+		// accounted through the owning tenant's own dropStream bookkeeping,
+		// not silently discarded.
+		fanIn: make(chan *logproto.Stream, len(authorized)*10),
+		done:  make(chan struct{}),
+	}
+
+	for _, tenantID := range authorized {
+		fanInServer := &tenantFanInServer{tenantID: tenantID, fanIn: mt.fanIn}
+
+		t, err := newTailer(tenantID, query, fanInServer, maxDroppedStreams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tailer for tenant %s: %w", tenantID, err)
+		}
+		fanInServer.tailer = t
+		mt.tailers[tenantID] = t
+	}
+
+	return mt, nil
+}
+
+// tenantFanInServer adapts a single tenant's tailer to the TailServer
+// interface by stamping its tenant ID onto every response and forwarding
+// it into the shared fan-in channel, rather than writing to a gRPC stream
+// directly. tailer is set once the tailer it backs has been constructed,
+// so overflow can be accounted through that tailer's own dropStream
+// bookkeeping.
+type tenantFanInServer struct {
+	tenantID string
+	fanIn    chan *logproto.Stream
+	tailer   *tailer
+}
+
+func (s *tenantFanInServer) Send(resp *logproto.TailResponse) error {
+	if resp.Stream == nil {
+		return nil
+	}
+
+	stream := *resp.Stream
+	stream.Labels = stampTenantID(stream.Labels, s.tenantID)
+
+	select {
+	case s.fanIn <- &stream:
+	default:
+		// The fan-in is full; account the loss through the tenant's own
+		// dropStream bookkeeping instead of discarding it silently, so
+		// droppedStreamsForTenant reflects every line this tenant lost,
+		// not just the ones dropped inside its own tailer loop.
+		s.tailer.dropStream(*resp.Stream)
+	}
+	return nil
+}
+
+func (s *tenantFanInServer) Context() context.Context {
+	return context.Background()
+}
+
+// stampTenantID appends the synthetic __tenant_id__ label to an already
+// serialized label set, e.g. `{app="foo"}` becomes
+// `{app="foo", __tenant_id__="org-a"}`.
+func stampTenantID(serialized, tenantID string) string {
+	if !strings.HasSuffix(serialized, "}") {
+		return serialized
+	}
+
+	trimmed := strings.TrimSuffix(serialized, "}")
+	sep := ", "
+	if trimmed == "{" {
+		sep = ""
+	}
+	return fmt.Sprintf(`%s%s%s=%q}`, trimmed, sep, tenantIDLabel, tenantID)
+}
+
+// run starts every tenant's tailer loop and the fan-in consumer that
+// writes merged responses to the caller's TailServer. It blocks until the
+// multiTenantTailer is closed or the underlying connection's context is
+// done.
+func (mt *multiTenantTailer) run() {
+	mt.tailersMtx.RLock()
+	for _, t := range mt.tailers {
+		t := t
+		mt.wg.Add(1)
+		go func() {
+			defer mt.wg.Done()
+			t.loop()
+		}()
+	}
+	mt.tailersMtx.RUnlock()
+
+	for {
+		select {
+		case stream := <-mt.fanIn:
+			_ = mt.conn.Send(&logproto.TailResponse{Stream: stream})
+		case <-mt.conn.Context().Done():
+			mt.close()
+			return
+		case <-mt.done:
+			return
+		}
+	}
+}
+
+// send routes a stream to the tailer for tenantID, if one exists and is
+// still being federated. Unknown tenants are ignored rather than erroring,
+// since the caller's authorized tenant set may be narrower than the set of
+// tenants actively producing streams.
+func (mt *multiTenantTailer) send(tenantID string, stream logproto.Stream, lbs labels.Labels) {
+	mt.tailersMtx.RLock()
+	t, ok := mt.tailers[tenantID]
+	mt.tailersMtx.RUnlock()
+	if !ok {
+		return
+	}
+	t.send(stream, lbs)
+}
+
+// droppedStreamsForTenant reports the dropped streams recorded for a
+// single tenant, keeping accounting per-tenant rather than merging it into
+// one global count across every federated tenant.
+func (mt *multiTenantTailer) droppedStreamsForTenant(tenantID string) []logproto.DroppedStream {
+	mt.tailersMtx.RLock()
+	t, ok := mt.tailers[tenantID]
+	mt.tailersMtx.RUnlock()
+	if !ok {
+		return nil
+	}
+	return append([]logproto.DroppedStream(nil), t.droppedStreams...)
+}
+
+// close shuts down every per-tenant tailer and stops the fan-in consumer.
+// It is safe to call close concurrently and more than once.
+func (mt *multiTenantTailer) close() {
+	mt.once.Do(func() {
+		close(mt.done)
+
+		mt.tailersMtx.RLock()
+		defer mt.tailersMtx.RUnlock()
+		for _, t := range mt.tailers {
+			t.close()
+		}
+	})
+	mt.wg.Wait()
+}
+
+// AuthorizeTenantIDs checks requested against allowed -- the tenant IDs
+// resolved from the caller's comma-delimited X-Scope-OrgID header. It
+// returns requested unchanged when every tenant in it is allowed, and
+// otherwise an error naming the first tenant the caller is not authorized
+// to tail.
+func AuthorizeTenantIDs(requested, allowed []string) ([]string, error) {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, id := range allowed {
+		allowedSet[strings.TrimSpace(id)] = struct{}{}
+	}
+
+	for _, id := range requested {
+		if _, ok := allowedSet[id]; !ok {
+			return nil, fmt.Errorf("caller is not authorized to tail tenant %s", id)
+		}
+	}
+	return requested, nil
+}
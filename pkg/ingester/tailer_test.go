@@ -2,6 +2,7 @@ package ingester
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"sync"
 	"testing"
@@ -136,6 +137,106 @@ func Test_TailerSendRace(t *testing.T) {
 	wg.Wait()
 }
 
+func TestMultiTenantTailer_ConcurrentCloseAcrossTenants(t *testing.T) {
+	runs := 100
+	numTenants := 5
+
+	stream := logproto.Stream{
+		Labels: `{type="test"}`,
+		Entries: []logproto.Entry{
+			{Timestamp: time.Unix(int64(1), 0), Line: "line 1"},
+			{Timestamp: time.Unix(int64(2), 0), Line: "line 2"},
+		},
+	}
+
+	tenantIDs := make([]string, numTenants)
+	for i := range tenantIDs {
+		tenantIDs[i] = fmt.Sprintf("org-%d", i)
+	}
+
+	for run := 0; run < runs; run++ {
+		mt, err := newMultiTenantTailer(tenantIDs, tenantIDs, stream.Labels, &fakeTailServer{}, 10)
+		require.NoError(t, err)
+		require.NotNil(t, mt)
+
+		routines := sync.WaitGroup{}
+		routines.Add(numTenants + 1)
+
+		for _, tenantID := range tenantIDs {
+			tenantID := tenantID
+			go assert.NotPanics(t, func() {
+				defer routines.Done()
+				time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+				mt.send(tenantID, stream, labels.Labels{{Name: "type", Value: "test"}})
+			})
+		}
+
+		go assert.NotPanics(t, func() {
+			defer routines.Done()
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+			mt.close()
+		})
+
+		routines.Wait()
+	}
+}
+
+func TestMultiTenantTailer_DroppedStreamAccountingIsPerTenant(t *testing.T) {
+	maxDroppedStreams := 10
+	tenantIDs := []string{"org-a", "org-b"}
+
+	mt, err := newMultiTenantTailer(tenantIDs, tenantIDs, `{app="foo"} |= "foo"`, &fakeTailServer{}, maxDroppedStreams)
+	require.NoError(t, err)
+
+	entry := logproto.Entry{Timestamp: time.Now(), Line: "foo"}
+
+	// Drop more streams for org-a than org-b, and assert the counts don't
+	// bleed into each other.
+	for i := 0; i < maxDroppedStreams; i++ {
+		mt.tailers["org-a"].dropStream(logproto.Stream{Entries: []logproto.Entry{entry}})
+	}
+	for i := 0; i < 3; i++ {
+		mt.tailers["org-b"].dropStream(logproto.Stream{Entries: []logproto.Entry{entry}})
+	}
+
+	require.Len(t, mt.droppedStreamsForTenant("org-a"), maxDroppedStreams)
+	require.Len(t, mt.droppedStreamsForTenant("org-b"), 3)
+	require.Nil(t, mt.droppedStreamsForTenant("org-unknown"))
+}
+
+func TestMultiTenantTailer_RejectsUnauthorizedTenants(t *testing.T) {
+	_, err := newMultiTenantTailer([]string{"org-a", "org-c"}, []string{"org-a", "org-b"}, `{app="foo"}`, &fakeTailServer{}, 10)
+	require.Error(t, err)
+}
+
+func TestMultiTenantTailer_FanInOverflowIsAccountedPerTenant(t *testing.T) {
+	tenantIDs := []string{"org-a"}
+
+	mt, err := newMultiTenantTailer(tenantIDs, tenantIDs, `{app="foo"}`, &fakeTailServer{}, 10)
+	require.NoError(t, err)
+
+	fanInServer := &tenantFanInServer{tenantID: "org-a", fanIn: mt.fanIn, tailer: mt.tailers["org-a"]}
+
+	// Fill the fan-in so the next Send has to take the overflow path.
+	for i := 0; i < cap(mt.fanIn); i++ {
+		mt.fanIn <- &logproto.Stream{}
+	}
+
+	require.NoError(t, fanInServer.Send(&logproto.TailResponse{Stream: &logproto.Stream{Labels: `{app="foo"}`}}))
+	require.Len(t, mt.droppedStreamsForTenant("org-a"), 1)
+}
+
+func TestAuthorizeTenantIDs(t *testing.T) {
+	allowed := []string{"org-a", "org-b"}
+
+	authorized, err := AuthorizeTenantIDs([]string{"org-a"}, allowed)
+	require.NoError(t, err)
+	require.Equal(t, []string{"org-a"}, authorized)
+
+	_, err = AuthorizeTenantIDs([]string{"org-a", "org-c"}, allowed)
+	require.Error(t, err)
+}
+
 func Test_IsMatching(t *testing.T) {
 	for _, tt := range []struct {
 		name     string